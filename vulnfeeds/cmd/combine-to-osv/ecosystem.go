@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// EcosystemTracker describes how to build a security tracker reference URL
+// for OSV records belonging to a given ecosystem.
+type EcosystemTracker struct {
+	// Ecosystem is the OSV ecosystem name, or prefix of it (e.g. "Alpine"
+	// matches "Alpine:v3.12"), that this tracker applies to.
+	Ecosystem string `json:"ecosystem"`
+	// URLTemplate is a URL whose final path segment is the (possibly
+	// transformed) CVE ID, joined on with url.JoinPath.
+	URLTemplate string `json:"url_template"`
+	// IDTransform optionally names a transformation applied to the CVE ID
+	// before it's joined onto URLTemplate. "" means no transformation.
+	IDTransform string `json:"id_transform,omitempty"`
+}
+
+// transformID applies the tracker's configured IDTransform to cveId.
+func (t EcosystemTracker) transformID(cveId string) (string, error) {
+	switch t.IDTransform {
+	case "", "none":
+		return cveId, nil
+	case "lowercase":
+		return strings.ToLower(cveId), nil
+	default:
+		return "", fmt.Errorf("unknown id_transform %q for ecosystem %q", t.IDTransform, t.Ecosystem)
+	}
+}
+
+// Reference builds the security tracker URL for cveId, or an error if the
+// tracker is misconfigured.
+func (t EcosystemTracker) Reference(cveId string) (string, error) {
+	id, err := t.transformID(cveId)
+	if err != nil {
+		return "", err
+	}
+	return url.JoinPath(t.URLTemplate, id)
+}
+
+// defaultEcosystemTrackers are the built-in trackers used when no registry
+// file is found at -ecosystemRegistryPath, or as a base that the registry
+// file's entries are merged on top of.
+var defaultEcosystemTrackers = []EcosystemTracker{
+	{Ecosystem: "Alpine", URLTemplate: "https://security.alpinelinux.org/vuln"},
+	{Ecosystem: "Debian", URLTemplate: "https://security-tracker.debian.org/tracker"},
+	{Ecosystem: "Ubuntu", URLTemplate: "https://ubuntu.com/security"},
+	{Ecosystem: "SUSE", URLTemplate: "https://www.suse.com/security/cve"},
+	{Ecosystem: "openSUSE", URLTemplate: "https://www.suse.com/security/cve"},
+	{Ecosystem: "Rocky Linux", URLTemplate: "https://errata.rockylinux.org/cve"},
+	{Ecosystem: "AlmaLinux", URLTemplate: "https://errata.almalinux.org/cve"},
+	{Ecosystem: "Wolfi", URLTemplate: "https://images.chainguard.dev/security/cve", IDTransform: "lowercase"},
+	{Ecosystem: "Chainguard", URLTemplate: "https://images.chainguard.dev/security/cve", IDTransform: "lowercase"},
+	{Ecosystem: "Red Hat", URLTemplate: "https://access.redhat.com/security/cve"},
+}
+
+// EcosystemRegistry maps an OSV ecosystem (or prefix) to the tracker used to
+// build its security tracker reference.
+type EcosystemRegistry map[string]EcosystemTracker
+
+// NewEcosystemRegistry builds the default registry from defaultEcosystemTrackers.
+func NewEcosystemRegistry() EcosystemRegistry {
+	registry := EcosystemRegistry{}
+	for _, tracker := range defaultEcosystemTrackers {
+		registry[tracker.Ecosystem] = tracker
+	}
+	return registry
+}
+
+// LoadEcosystemRegistry builds the default registry and then merges in (or
+// overrides with) the trackers declared in the JSON file at path. A missing
+// file is not an error: it just means the defaults are used as-is, which
+// lets -ecosystemRegistryPath be left at its default in most deployments.
+func LoadEcosystemRegistry(path string) (EcosystemRegistry, error) {
+	registry := NewEcosystemRegistry()
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ecosystem registry %q: %w", path, err)
+	}
+	defer file.Close()
+
+	var extra []EcosystemTracker
+	if err := json.NewDecoder(file).Decode(&extra); err != nil {
+		return nil, fmt.Errorf("failed to decode ecosystem registry %q: %w", path, err)
+	}
+	for _, tracker := range extra {
+		registry[tracker.Ecosystem] = tracker
+	}
+
+	return registry, nil
+}
+
+// Match returns the tracker whose Ecosystem is a prefix of ecosystem, if any,
+// checking entries in a deterministic (sorted) order.
+func (r EcosystemRegistry) Match(ecosystem string) (EcosystemTracker, bool) {
+	keys := make([]string, 0, len(r))
+	for key := range r {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if strings.HasPrefix(ecosystem, key) {
+			return r[key], true
+		}
+	}
+	return EcosystemTracker{}, false
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEcosystemTrackerReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracker EcosystemTracker
+		cveId   string
+		want    string
+	}{
+		{
+			name:    "no transform",
+			tracker: EcosystemTracker{Ecosystem: "Alpine", URLTemplate: "https://security.alpinelinux.org/vuln"},
+			cveId:   "CVE-2020-1234",
+			want:    "https://security.alpinelinux.org/vuln/CVE-2020-1234",
+		},
+		{
+			name:    "lowercase transform",
+			tracker: EcosystemTracker{Ecosystem: "Wolfi", URLTemplate: "https://images.chainguard.dev/security/cve", IDTransform: "lowercase"},
+			cveId:   "CVE-2020-1234",
+			want:    "https://images.chainguard.dev/security/cve/cve-2020-1234",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.tracker.Reference(tc.cveId)
+			if err != nil {
+				t.Fatalf("Reference() returned error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Reference() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEcosystemTrackerReferenceUnknownTransform(t *testing.T) {
+	tracker := EcosystemTracker{Ecosystem: "Bogus", URLTemplate: "https://example.com", IDTransform: "uppercase"}
+	if _, err := tracker.Reference("CVE-2020-1234"); err == nil {
+		t.Fatal("Reference() with an unknown id_transform should return an error")
+	}
+}
+
+func TestEcosystemRegistryMatch(t *testing.T) {
+	registry := NewEcosystemRegistry()
+
+	tests := []struct {
+		ecosystem string
+		wantFound bool
+		want      string
+	}{
+		{"Alpine:v3.18", true, "Alpine"},
+		{"Debian:11", true, "Debian"},
+		{"openSUSE Tumbleweed", true, "openSUSE"},
+		{"PyPI", false, ""},
+	}
+	for _, tc := range tests {
+		tracker, ok := registry.Match(tc.ecosystem)
+		if ok != tc.wantFound {
+			t.Errorf("Match(%q) found = %v, want %v", tc.ecosystem, ok, tc.wantFound)
+			continue
+		}
+		if ok && tracker.Ecosystem != tc.want {
+			t.Errorf("Match(%q) = %q, want %q", tc.ecosystem, tracker.Ecosystem, tc.want)
+		}
+	}
+}
+
+func TestLoadEcosystemRegistryMerge(t *testing.T) {
+	extraPath := filepath.Join(t.TempDir(), "extra.json")
+	extraJSON := `[
+		{"ecosystem": "Alpine", "url_template": "https://example.com/alpine"},
+		{"ecosystem": "Gentoo", "url_template": "https://example.com/gentoo"}
+	]`
+	if err := os.WriteFile(extraPath, []byte(extraJSON), 0644); err != nil {
+		t.Fatalf("failed to write test fixture: %v", err)
+	}
+
+	registry, err := LoadEcosystemRegistry(extraPath)
+	if err != nil {
+		t.Fatalf("LoadEcosystemRegistry() returned error: %v", err)
+	}
+
+	if tracker, ok := registry.Match("Alpine:v3.18"); !ok || tracker.URLTemplate != "https://example.com/alpine" {
+		t.Errorf("expected the registry file's Alpine entry to override the built-in one, got %+v, found=%v", tracker, ok)
+	}
+	if _, ok := registry.Match("Gentoo"); !ok {
+		t.Error("expected the registry file's Gentoo entry to be present")
+	}
+	if _, ok := registry.Match("Debian"); !ok {
+		t.Error("expected built-in entries not mentioned in the file to still be present")
+	}
+}
+
+func TestLoadEcosystemRegistryMissingFile(t *testing.T) {
+	registry, err := LoadEcosystemRegistry(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatalf("LoadEcosystemRegistry() with a missing file should not error, got: %v", err)
+	}
+	if _, ok := registry.Match("Alpine"); !ok {
+		t.Error("expected built-in entries when the registry file doesn't exist")
+	}
+}
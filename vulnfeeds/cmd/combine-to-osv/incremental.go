@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+// changesSummary is written as changes.json when -prevOSVPath is set,
+// recording what a run actually did relative to the previous output
+// directory so the downstream pipeline (and human reviewers) don't have to
+// diff tens of thousands of files to find out.
+type changesSummary struct {
+	Added     []string `json:"added,omitempty"`
+	Modified  []string `json:"modified,omitempty"`
+	Withdrawn []string `json:"withdrawn,omitempty"`
+	Unchanged []string `json:"unchanged,omitempty"`
+}
+
+// loadPrevOSV reads every OSV record in prevOSVPath, keyed by ID. A missing
+// directory is treated as "no previous run" rather than an error, so
+// -prevOSVPath can point at a path that doesn't exist yet on the first run.
+func loadPrevOSV(prevOSVPath string) (map[cves.CVEID]*vulns.Vulnerability, error) {
+	prev := map[cves.CVEID]*vulns.Vulnerability{}
+	if prevOSVPath == "" {
+		return prev, nil
+	}
+
+	entries, err := os.ReadDir(prevOSVPath)
+	if os.IsNotExist(err) {
+		return prev, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir %q: %w", prevOSVPath, err)
+	}
+
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || !strings.HasPrefix(id, "CVE-") {
+			continue
+		}
+
+		file, err := os.Open(path.Join(prevOSVPath, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %q: %w", entry.Name(), err)
+		}
+		var osv vulns.Vulnerability
+		err = json.NewDecoder(file).Decode(&osv)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %q: %w", entry.Name(), err)
+		}
+		prev[cves.CVEID(id)] = &osv
+	}
+	return prev, nil
+}
+
+// applyIncremental reconciles combinedData against the previous run's
+// output in prevOSV. It returns only the records that actually changed, so
+// writeOSVFile only has to rewrite those, plus a summary of what changed.
+func applyIncremental(combinedData map[cves.CVEID]*vulns.Vulnerability, prevOSV map[cves.CVEID]*vulns.Vulnerability) (map[cves.CVEID]*vulns.Vulnerability, changesSummary) {
+	changed := map[cves.CVEID]*vulns.Vulnerability{}
+	var summary changesSummary
+
+	for id, osv := range combinedData {
+		prev, existed := prevOSV[id]
+		if !existed {
+			summary.Added = append(summary.Added, string(id))
+			changed[id] = osv
+			continue
+		}
+
+		if vulnerabilityContentEqual(osv, prev) {
+			summary.Unchanged = append(summary.Unchanged, string(id))
+			continue
+		}
+
+		if osv.Withdrawn != "" && prev.Withdrawn == "" {
+			summary.Withdrawn = append(summary.Withdrawn, string(id))
+		} else {
+			summary.Modified = append(summary.Modified, string(id))
+		}
+		changed[id] = osv
+	}
+
+	for id := range prevOSV {
+		if _, stillPresent := combinedData[id]; !stillPresent {
+			summary.Withdrawn = append(summary.Withdrawn, string(id))
+		}
+	}
+
+	return changed, summary
+}
+
+// vulnerabilityContentEqual reports whether osv and prev are identical
+// other than their Modified timestamp, i.e. whether the only delta would be
+// a re-serialization artifact rather than an actual content change.
+func vulnerabilityContentEqual(osv, prev *vulns.Vulnerability) bool {
+	osvCopy := *osv
+	osvCopy.Modified = prev.Modified
+
+	aBytes, errA := json.Marshal(osvCopy)
+	bBytes, errB := json.Marshal(prev)
+	return errA == nil && errB == nil && bytes.Equal(aBytes, bBytes)
+}
+
+// writeChangesSummary writes changes.json next to the OSV output.
+func writeChangesSummary(osvOutputPath string, summary changesSummary) error {
+	return writeJSONFile(path.Join(osvOutputPath, "changes.json"), summary)
+}
+
+// copyForwardUnchanged copies each unchanged record's JSON file from
+// prevOSVPath into osvOutputPath. writeOSVFile only rewrites added/modified
+// records, which is only a complete database on its own when osvOutputPath
+// and prevOSVPath are the same directory (an in-place update); -prevOSVPath's
+// own doc string also allows it to be "a mirror of" a prior run, e.g. a
+// scratch directory generated fresh before being synced to a bucket, so
+// unchanged records have to be copied forward explicitly in that case.
+func copyForwardUnchanged(summary changesSummary, prevOSVPath, osvOutputPath string) error {
+	if prevOSVPath == "" || prevOSVPath == osvOutputPath {
+		return nil
+	}
+	for _, id := range summary.Unchanged {
+		name := id + ".json"
+		contents, err := os.ReadFile(path.Join(prevOSVPath, name))
+		if err != nil {
+			return fmt.Errorf("failed to read %q from -prevOSVPath: %w", name, err)
+		}
+		if err := os.WriteFile(path.Join(osvOutputPath, name), contents, 0644); err != nil {
+			return fmt.Errorf("failed to copy %q into osvOutputPath: %w", name, err)
+		}
+	}
+	return nil
+}
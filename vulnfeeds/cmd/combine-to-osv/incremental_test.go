@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+func TestVulnerabilityContentEqual(t *testing.T) {
+	base := &vulns.Vulnerability{ID: "CVE-2020-1234", Modified: "2020-01-01T00:00:00Z", Published: "2020-01-01T00:00:00Z"}
+
+	onlyModifiedDiffers := *base
+	onlyModifiedDiffers.Modified = "2020-06-01T00:00:00Z"
+	if !vulnerabilityContentEqual(&onlyModifiedDiffers, base) {
+		t.Error("expected records differing only in Modified to be considered content-equal")
+	}
+
+	contentDiffers := *base
+	contentDiffers.Withdrawn = "2020-06-01T00:00:00Z"
+	if vulnerabilityContentEqual(&contentDiffers, base) {
+		t.Error("expected records with a real content difference to not be considered content-equal")
+	}
+}
+
+func TestApplyIncremental(t *testing.T) {
+	prev := map[cves.CVEID]*vulns.Vulnerability{
+		"CVE-2020-0001": {ID: "CVE-2020-0001", Modified: "2020-01-01T00:00:00Z"},
+		"CVE-2020-0002": {ID: "CVE-2020-0002", Modified: "2020-01-01T00:00:00Z"},
+		"CVE-2020-0003": {ID: "CVE-2020-0003", Modified: "2020-01-01T00:00:00Z"},
+	}
+	current := map[cves.CVEID]*vulns.Vulnerability{
+		"CVE-2020-0001": {ID: "CVE-2020-0001", Modified: "2020-01-01T00:00:00Z"},
+		"CVE-2020-0002": {ID: "CVE-2020-0002", Modified: "2020-06-01T00:00:00Z", Withdrawn: "2020-06-01T00:00:00Z"},
+		"CVE-2020-0004": {ID: "CVE-2020-0004", Modified: "2020-06-01T00:00:00Z"},
+		// CVE-2020-0003 is deliberately absent: it disappeared upstream.
+	}
+
+	changed, summary := applyIncremental(current, prev)
+
+	if len(changed) != 2 {
+		t.Fatalf("expected 2 changed records, got %d: %+v", len(changed), changed)
+	}
+	if _, ok := changed["CVE-2020-0002"]; !ok {
+		t.Error("expected CVE-2020-0002 (withdrawn) to be in the changed set")
+	}
+	if _, ok := changed["CVE-2020-0004"]; !ok {
+		t.Error("expected CVE-2020-0004 (added) to be in the changed set")
+	}
+
+	assertStringSet(t, "Added", summary.Added, []string{"CVE-2020-0004"})
+	assertStringSet(t, "Unchanged", summary.Unchanged, []string{"CVE-2020-0001"})
+	assertStringSet(t, "Withdrawn", summary.Withdrawn, []string{"CVE-2020-0002", "CVE-2020-0003"})
+	assertStringSet(t, "Modified", summary.Modified, nil)
+}
+
+func assertStringSet(t *testing.T, field string, got, want []string) {
+	t.Helper()
+	gotSorted := append([]string(nil), got...)
+	wantSorted := append([]string(nil), want...)
+	sort.Strings(gotSorted)
+	sort.Strings(wantSorted)
+	if len(gotSorted) != len(wantSorted) {
+		t.Errorf("%s = %v, want %v", field, got, want)
+		return
+	}
+	for i := range gotSorted {
+		if gotSorted[i] != wantSorted[i] {
+			t.Errorf("%s = %v, want %v", field, got, want)
+			return
+		}
+	}
+}
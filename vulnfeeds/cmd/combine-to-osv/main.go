@@ -3,27 +3,29 @@ package main
 import (
 	"encoding/json"
 	"flag"
-	"net/url"
+	"fmt"
 	"os"
 	"path"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/internal/osvlint"
 	"github.com/google/osv/vulnfeeds/utility"
 	"github.com/google/osv/vulnfeeds/vulns"
 )
 
 const (
-	defaultCvePath        = "cve_jsons"
-	defaultPartsInputPath = "parts"
-	defaultOSVOutputPath  = "osv_output"
-	defaultCVEListPath    = "."
-
-	alpineEcosystem          = "Alpine"
-	alpineSecurityTrackerURL = "https://security.alpinelinux.org/vuln"
-	debianEcosystem          = "Debian"
-	debianSecurityTrackerURL = "https://security-tracker.debian.org/tracker"
+	defaultCvePath               = "cve_jsons"
+	defaultPartsInputPath        = "parts"
+	defaultOSVOutputPath         = "osv_output"
+	defaultCVEListPath           = "."
+	defaultEcosystemRegistryPath = "ecosystem_trackers.json"
+	defaultPartsGitCachePath     = "parts_git_cache"
+	defaultLintMode              = string(osvlint.ModeWarn)
 )
 
 var Logger utility.LoggerWrapper
@@ -37,9 +39,30 @@ func main() {
 	partsInputPath := flag.String("partsPath", defaultPartsInputPath, "Path to CVE file")
 	osvOutputPath := flag.String("osvOutputPath", defaultOSVOutputPath, "Path to CVE file")
 	cveListPath := flag.String("cveListPath", defaultCVEListPath, "Path to clone of https://github.com/CVEProject/cvelistV5")
+	ecosystemRegistryPath := flag.String("ecosystemRegistryPath", defaultEcosystemRegistryPath, "Path to a JSON file of additional/overriding ecosystem security tracker entries")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of concurrent workers used to load, combine and write OSV records")
+	partsGit := flag.String("partsGit", "", "Comma-separated ecosystem=repoURL pairs to ingest parts from directly, e.g. alpine=https://github.com/alpinelinux/alpine-secdb")
+	partsGitCachePath := flag.String("partsGitCachePath", defaultPartsGitCachePath, "Path to cache -partsGit clones in")
+	prevOSVPath := flag.String("prevOSVPath", "", "Path to a previous run's osvOutputPath (or a mirror of it), for incremental output and changes.json")
+	lintModeFlag := flag.String("lintMode", defaultLintMode, "Lint mode for generated OSV records: warn, fail, or skip")
 	flag.Parse()
 
-	err := os.MkdirAll(*cvePath, 0755)
+	ecosystemRegistry, err := LoadEcosystemRegistry(*ecosystemRegistryPath)
+	if err != nil {
+		Logger.Fatalf("Failed to load ecosystem registry: %s", err)
+	}
+
+	lintMode, err := osvlint.ParseMode(*lintModeFlag)
+	if err != nil {
+		Logger.Fatalf("Invalid -lintMode: %s", err)
+	}
+
+	gitPartsSources, err := parseGitPartsSources(*partsGit)
+	if err != nil {
+		Logger.Fatalf("Failed to parse -partsGit: %s", err)
+	}
+
+	err = os.MkdirAll(*cvePath, 0755)
 	if err != nil {
 		Logger.Fatalf("Can't create output path: %s", err)
 	}
@@ -49,9 +72,54 @@ func main() {
 	}
 
 	allCves := loadAllCVEs(*cvePath)
-	allParts, cveModifiedMap := loadParts(*partsInputPath)
-	combinedData := combineIntoOSV(allCves, allParts, *cveListPath, cveModifiedMap)
-	writeOSVFile(combinedData, *osvOutputPath)
+	allParts, cveModifiedMap, partErrs := loadParts(*partsInputPath, *workers)
+	for _, partErr := range partErrs {
+		Logger.Warnf("Skipping malformed part file: %s", partErr)
+	}
+
+	partsSourceSHAs := map[string]string{}
+	if len(gitPartsSources) > 0 {
+		shas, gitErrs := loadGitParts(gitPartsSources, *partsGitCachePath, wrapPartsOutput(allParts, cveModifiedMap), *workers)
+		for _, gitErr := range gitErrs {
+			Logger.Warnf("Skipping malformed git-sourced advisory: %s", gitErr)
+		}
+		partsSourceSHAs = shas
+	}
+
+	prevOSV, err := loadPrevOSV(*prevOSVPath)
+	if err != nil {
+		Logger.Fatalf("Failed to load -prevOSVPath: %s", err)
+	}
+
+	combinedData := combineIntoOSV(allCves, allParts, *cveListPath, cveModifiedMap, ecosystemRegistry, *workers, prevOSV)
+
+	combinedData, lintFindings := lintCombinedData(combinedData, lintMode)
+	if lintMode != osvlint.ModeSkip {
+		if err := writeJSONFile(path.Join(*osvOutputPath, "lint-report.json"), lintFindings); err != nil {
+			Logger.Fatalf("Failed to write lint-report.json: %s", err)
+		}
+	}
+
+	changedData, changes := applyIncremental(combinedData, prevOSV)
+	writeOSVFile(changedData, *osvOutputPath, *workers)
+	if err := copyForwardUnchanged(changes, *prevOSVPath, *osvOutputPath); err != nil {
+		Logger.Fatalf("Failed to copy forward unchanged records: %s", err)
+	}
+	if *prevOSVPath != "" {
+		if err := writeChangesSummary(*osvOutputPath, changes); err != nil {
+			Logger.Fatalf("Failed to write changes.json: %s", err)
+		}
+	}
+
+	if err := writeIndex(combinedData, *osvOutputPath); err != nil {
+		Logger.Fatalf("Failed to write index.json: %s", err)
+	}
+	for _, byEcosystemErr := range writeByEcosystem(combinedData, *osvOutputPath) {
+		Logger.Warnf("Skipping by-ecosystem listing: %s", byEcosystemErr)
+	}
+	if err := writeManifest(*osvOutputPath, len(allCves), len(combinedData), partsSourceSHAs, time.Now()); err != nil {
+		Logger.Fatalf("Failed to write manifest.json: %s", err)
+	}
 }
 
 // getModifiedTime gets the modification time of a given file
@@ -67,51 +135,117 @@ func getModifiedTime(filePath string) (time.Time, error) {
 	return parsedTime, err
 }
 
-// loadInnerParts loads second level folder for the loadParts function
+// partsOutput is a concurrency-safe accumulator for the per-CVE PackageInfo
+// lists and modified-times that loadInnerParts builds, shared by the workers
+// that decode part files in parallel.
+type partsOutput struct {
+	mu                   sync.Mutex
+	output               map[cves.CVEID][]vulns.PackageInfo
+	cvePartsModifiedTime map[cves.CVEID]time.Time
+}
+
+func newPartsOutput() *partsOutput {
+	return &partsOutput{
+		output:               map[cves.CVEID][]vulns.PackageInfo{},
+		cvePartsModifiedTime: map[cves.CVEID]time.Time{},
+	}
+}
+
+// wrapPartsOutput lets callers fan further additions into maps they already
+// hold, such as merging -partsGit sources into what loadParts built.
+func wrapPartsOutput(output map[cves.CVEID][]vulns.PackageInfo, cvePartsModifiedTime map[cves.CVEID]time.Time) *partsOutput {
+	return &partsOutput{output: output, cvePartsModifiedTime: cvePartsModifiedTime}
+}
+
+func (p *partsOutput) add(cveId cves.CVEID, pkgInfos []vulns.PackageInfo, modifiedTime time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.output[cveId] = append(p.output[cveId], pkgInfos...)
+	if existingDate, exists := p.cvePartsModifiedTime[cveId]; !exists || modifiedTime.After(existingDate) {
+		p.cvePartsModifiedTime[cveId] = modifiedTime
+	}
+}
+
+// sortEntries deterministically orders the PackageInfo slice of each given
+// CVE ID, so that concurrent add calls that raced to append to the same ID
+// don't leave its final order dependent on goroutine scheduling.
+func (p *partsOutput) sortEntries(ids map[cves.CVEID]bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id := range ids {
+		sortPackageInfos(p.output[id])
+	}
+}
+
+// sortPackageInfos orders infos by ecosystem, then package name, then fixed
+// version, so two runs over the same inputs produce byte-identical output.
+func sortPackageInfos(infos []vulns.PackageInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Ecosystem != infos[j].Ecosystem {
+			return infos[i].Ecosystem < infos[j].Ecosystem
+		}
+		if infos[i].PkgName != infos[j].PkgName {
+			return infos[i].PkgName < infos[j].PkgName
+		}
+		return infos[i].FixedVersion < infos[j].FixedVersion
+	})
+}
+
+// loadInnerParts loads second level folder for the loadParts function,
+// fanning the JSON decoding of its part files out across workers. A
+// malformed part file is recorded as an error rather than aborting the run.
 //
 // Parameters:
 //   - innerPartInputPath: The inner part path, such as "parts/alpine"
-//   - output: A map to store all PackageInfos for each CVE ID
-//   - cvePartsModifiedTime: A map tracking the latest modification time of each CVE part files
-func loadInnerParts(innerPartInputPath string, output map[cves.CVEID][]vulns.PackageInfo, cvePartsModifiedTime map[cves.CVEID]time.Time) {
+//   - out: The concurrency-safe accumulator to add loaded PackageInfos to
+//   - workers: The number of part files to decode concurrently
+func loadInnerParts(innerPartInputPath string, out *partsOutput, workers int) []error {
 	dirInner, err := os.ReadDir(innerPartInputPath)
 	if err != nil {
-		Logger.Fatalf("Failed to read dir %q: %s", innerPartInputPath, err)
+		return []error{fmt.Errorf("failed to read dir %q: %w", innerPartInputPath, err)}
 	}
+
+	var entries []os.DirEntry
 	for _, entryInner := range dirInner {
-		if !strings.HasSuffix(entryInner.Name(), ".json") {
-			continue
+		if strings.HasSuffix(entryInner.Name(), ".json") {
+			entries = append(entries, entryInner)
 		}
+	}
+
+	var errsMu sync.Mutex
+	var errs []error
+	runWorkerPool(workers, entries, func(entryInner os.DirEntry) {
 		filePath := path.Join(innerPartInputPath, entryInner.Name())
 		file, err := os.Open(filePath)
 		if err != nil {
-			Logger.Fatalf("Failed to open PackageInfo JSON %q: %s", path.Join(innerPartInputPath, entryInner.Name()), err)
+			errsMu.Lock()
+			errs = append(errs, fmt.Errorf("failed to open PackageInfo JSON %q: %w", filePath, err))
+			errsMu.Unlock()
+			return
 		}
 		defer file.Close()
 		var pkgInfos []vulns.PackageInfo
-		err = json.NewDecoder(file).Decode(&pkgInfos)
-		if err != nil {
-			Logger.Fatalf("Failed to decode %q: %s", file.Name(), err)
+		if err := json.NewDecoder(file).Decode(&pkgInfos); err != nil {
+			errsMu.Lock()
+			errs = append(errs, fmt.Errorf("failed to decode %q: %w", filePath, err))
+			errsMu.Unlock()
+			return
 		}
 
 		// Turns CVE-2022-12345.alpine.json into CVE-2022-12345
 		cveId := cves.CVEID(strings.Split(entryInner.Name(), ".")[0])
-		output[cveId] = append(output[cveId], pkgInfos...)
-
-		Logger.Infof(
-			"Loaded Item: %s", entryInner.Name())
 
-		// Updates the latest OSV parts modified time of each CVE
 		modifiedTime, err := getModifiedTime(filePath)
 		if err != nil {
 			Logger.Warnf("Failed to get modified time of %s: %s", filePath, err)
-			continue
-		}
-		existingDate, exists := cvePartsModifiedTime[cveId]
-		if !exists || modifiedTime.After(existingDate) {
-			cvePartsModifiedTime[cveId] = modifiedTime
+			modifiedTime = time.Time{}
 		}
-	}
+		out.add(cveId, pkgInfos, modifiedTime)
+
+		Logger.Infof("Loaded Item: %s", entryInner.Name())
+	})
+
+	return errs
 }
 
 // loadParts loads files generated by other executables in the cmd folder.
@@ -125,34 +259,47 @@ func loadInnerParts(innerPartInputPath string, output map[cves.CVEID][]vulns.Pac
 //   - debianParts/
 //   - ...
 //
+// The part files within each ecosystem's directory are decoded concurrently
+// across up to workers goroutines.
+//
 // ## Returns
 // A mapping of "CVE-ID": []<Affected Package Information>
 // A mapping of "CVE-ID": time.Time (the latest modified time of its part files)
-func loadParts(partsInputPath string) (map[cves.CVEID][]vulns.PackageInfo, map[cves.CVEID]time.Time) {
+// Every per-file error encountered, none of which abort the run
+func loadParts(partsInputPath string, workers int) (map[cves.CVEID][]vulns.PackageInfo, map[cves.CVEID]time.Time, []error) {
 	dir, err := os.ReadDir(partsInputPath)
 	if err != nil {
 		Logger.Fatalf("Failed to read dir %q: %s", partsInputPath, err)
 	}
-	output := map[cves.CVEID][]vulns.PackageInfo{}
-	cvePartsModifiedTime := make(map[cves.CVEID]time.Time)
+
+	out := newPartsOutput()
+	var errs []error
 	for _, entry := range dir {
 		if !entry.IsDir() {
 			Logger.Warnf("Unexpected file entry %q in %s", entry.Name(), partsInputPath)
 			continue
 		}
-		// map is already a reference type, so no need to pass in a pointer
-		loadInnerParts(path.Join(partsInputPath, entry.Name()), output, cvePartsModifiedTime)
+		// out guards its maps with a mutex, so every directory's workers can share the same pointer
+		errs = append(errs, loadInnerParts(path.Join(partsInputPath, entry.Name()), out, workers)...)
 	}
-	return output, cvePartsModifiedTime
+	return out.output, out.cvePartsModifiedTime, errs
 }
 
 // combineIntoOSV creates OSV entry by combining loaded CVEs from NVD and PackageInfo information from security advisories.
-func combineIntoOSV(loadedCves map[cves.CVEID]cves.Vulnerability, allParts map[cves.CVEID][]vulns.PackageInfo, cveList string, cvePartsModifiedTime map[cves.CVEID]time.Time) map[cves.CVEID]*vulns.Vulnerability {
+func combineIntoOSV(loadedCves map[cves.CVEID]cves.Vulnerability, allParts map[cves.CVEID][]vulns.PackageInfo, cveList string, cvePartsModifiedTime map[cves.CVEID]time.Time, ecosystemRegistry EcosystemRegistry, workers int, prevOSV map[cves.CVEID]*vulns.Vulnerability) map[cves.CVEID]*vulns.Vulnerability {
 	Logger.Infof("Begin writing OSV files from %d parts", len(allParts))
+
+	cveIds := make([]cves.CVEID, 0, len(loadedCves))
+	for cveId := range loadedCves {
+		cveIds = append(cveIds, cveId)
+	}
+
+	var convertedCvesMu sync.Mutex
 	convertedCves := map[cves.CVEID]*vulns.Vulnerability{}
-	for cveId, cve := range loadedCves {
+	runWorkerPool(workers, cveIds, func(cveId cves.CVEID) {
+		cve := loadedCves[cveId]
 		if len(allParts[cveId]) == 0 {
-			continue
+			return
 		}
 		convertedCve, _ := vulns.FromCVE(cveId, cve.CVE)
 		if len(cveList) > 0 {
@@ -166,44 +313,98 @@ func combineIntoOSV(loadedCves map[cves.CVEID]cves.Vulnerability, allParts map[c
 			}
 		}
 
-		addedDebianURL := false
-		addedAlpineURL := false
+		addedTrackerURL := map[string]bool{}
 		for _, pkgInfo := range allParts[cveId] {
 			convertedCve.AddPkgInfo(pkgInfo)
-			if strings.HasPrefix(pkgInfo.Ecosystem, debianEcosystem) && !addedDebianURL {
-				addReference(string(cveId), debianEcosystem, convertedCve)
-				addedDebianURL = true
-			} else if strings.HasPrefix(pkgInfo.Ecosystem, alpineEcosystem) && !addedAlpineURL {
-				addReference(string(cveId), alpineEcosystem, convertedCve)
-				addedAlpineURL = true
+			tracker, ok := ecosystemRegistry.Match(pkgInfo.Ecosystem)
+			if !ok || addedTrackerURL[tracker.Ecosystem] {
+				continue
 			}
+			if err := addReference(string(cveId), tracker, convertedCve); err != nil {
+				Logger.Warnf("Failed to build security tracker reference for %s/%s: %s", cveId, tracker.Ecosystem, err)
+				continue
+			}
+			addedTrackerURL[tracker.Ecosystem] = true
 		}
 
-		cveModified, _ := time.Parse(time.RFC3339, convertedCve.Modified)
-		if cvePartsModifiedTime[cveId].After(cveModified) {
-			convertedCve.Modified = cvePartsModifiedTime[cveId].Format(time.RFC3339)
+		latestModified, _ := time.Parse(time.RFC3339, convertedCve.Modified)
+		if cvePartsModifiedTime[cveId].After(latestModified) {
+			latestModified = cvePartsModifiedTime[cveId]
+			convertedCve.Modified = latestModified.Format(time.RFC3339)
+		}
+		// Neither the CVE nor any part file justified a newer Modified than
+		// the previous run recorded: keep the previous run's timestamp so a
+		// pure re-serialization doesn't look like a content change.
+		if prevCve, ok := prevOSV[cveId]; ok {
+			if prevModified, err := time.Parse(time.RFC3339, prevCve.Modified); err == nil && !latestModified.After(prevModified) {
+				convertedCve.Modified = prevCve.Modified
+			}
 		}
+
+		convertedCvesMu.Lock()
 		convertedCves[cveId] = convertedCve
-	}
+		convertedCvesMu.Unlock()
+	})
 	Logger.Infof("Ended writing %d OSV files", len(convertedCves))
 	return convertedCves
 }
 
-// writeOSVFile writes out the given osv objects into individual json files
-func writeOSVFile(osvData map[cves.CVEID]*vulns.Vulnerability, osvOutputPath string) {
+// lintCombinedData runs osvlint.Lint over every record in combinedData.
+// In osvlint.ModeFail, offending records are excluded from the returned map
+// and logged rather than aborting the run; in osvlint.ModeWarn they're kept
+// and only logged. Every finding is returned regardless of mode, for
+// lint-report.json.
+func lintCombinedData(combinedData map[cves.CVEID]*vulns.Vulnerability, mode osvlint.Mode) (map[cves.CVEID]*vulns.Vulnerability, []osvlint.Finding) {
+	if mode == osvlint.ModeSkip {
+		return combinedData, nil
+	}
+
+	var allFindings []osvlint.Finding
+	linted := make(map[cves.CVEID]*vulns.Vulnerability, len(combinedData))
+	for id, osv := range combinedData {
+		findings := osvlint.Lint(id, osv, osvlint.IsKnownEcosystem)
+		allFindings = append(allFindings, findings...)
+		if len(findings) == 0 {
+			linted[id] = osv
+			continue
+		}
+
+		for _, finding := range findings {
+			Logger.Warnf("Lint finding for %s: %s: %s", finding.ID, finding.Rule, finding.Message)
+		}
+		if mode == osvlint.ModeFail {
+			Logger.Warnf("Excluding %s from output: %d lint finding(s)", id, len(findings))
+			continue
+		}
+		linted[id] = osv
+	}
+	return linted, allFindings
+}
+
+// writeOSVFile writes out the given osv objects into individual json files,
+// using up to workers goroutines to write concurrently.
+func writeOSVFile(osvData map[cves.CVEID]*vulns.Vulnerability, osvOutputPath string, workers int) {
+	type entry struct {
+		id  cves.CVEID
+		osv *vulns.Vulnerability
+	}
+	entries := make([]entry, 0, len(osvData))
 	for vId, osv := range osvData {
-		file, err := os.OpenFile(path.Join(osvOutputPath, string(vId)+".json"), os.O_CREATE|os.O_RDWR, 0644)
+		entries = append(entries, entry{id: vId, osv: osv})
+	}
+
+	runWorkerPool(workers, entries, func(e entry) {
+		file, err := os.OpenFile(path.Join(osvOutputPath, string(e.id)+".json"), os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
 		if err != nil {
 			Logger.Fatalf("Failed to create/open file to write: %s", err)
 		}
+		defer file.Close()
 		encoder := json.NewEncoder(file)
 		encoder.SetIndent("", "  ")
-		err = encoder.Encode(osv)
-		if err != nil {
+		if err := encoder.Encode(e.osv); err != nil {
 			Logger.Fatalf("Failed to encode OSVs")
 		}
-		file.Close()
-	}
+	})
 
 	Logger.Infof("Successfully written %d OSV files", len(osvData))
 }
@@ -240,18 +441,12 @@ func loadAllCVEs(cvePath string) map[cves.CVEID]cves.Vulnerability {
 	return result
 }
 
-// addReference adds the related security tracker URL to a given vulnerability's references
-func addReference(cveId string, ecosystem string, convertedCve *vulns.Vulnerability) {
-	securityReference := vulns.Reference{Type: "ADVISORY"}
-	if ecosystem == alpineEcosystem {
-		securityReference.URL, _ = url.JoinPath(alpineSecurityTrackerURL, cveId)
-	} else if ecosystem == debianEcosystem {
-		securityReference.URL, _ = url.JoinPath(debianSecurityTrackerURL, cveId)
-	}
-
-	if securityReference.URL == "" {
-		return
+// addReference adds the given tracker's security tracker URL to a vulnerability's references.
+func addReference(cveId string, tracker EcosystemTracker, convertedCve *vulns.Vulnerability) error {
+	refURL, err := tracker.Reference(cveId)
+	if err != nil {
+		return err
 	}
-
-	convertedCve.References = append(convertedCve.References, securityReference)
+	convertedCve.References = append(convertedCve.References, vulns.Reference{Type: "ADVISORY", URL: refURL})
+	return nil
 }
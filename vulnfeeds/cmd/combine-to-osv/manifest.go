@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+// generatorVersion identifies the version of this generator that wrote a
+// given manifest.json, so downstream consumers can tell what produced a dump.
+const generatorVersion = "combine-to-osv/1"
+
+// indexEntry is one row of index.json: enough for a mirror to do a
+// conditional fetch of the full OSV record without downloading it.
+type indexEntry struct {
+	ID       string `json:"id"`
+	Modified string `json:"modified"`
+}
+
+// manifestData describes the database dump that writeOSVFile produced, so
+// downstream mirrors and human reviewers can tell what produced it and from
+// what inputs.
+type manifestData struct {
+	GeneratorVersion string            `json:"generator_version"`
+	InputCveCount    int               `json:"input_cve_count"`
+	OutputCount      int               `json:"output_count"`
+	PartsSourceSHAs  map[string]string `json:"parts_source_shas,omitempty"`
+	RunTimestamp     string            `json:"run_timestamp"`
+}
+
+// writeIndex writes index.json, listing every ID with its Modified
+// timestamp, so downstream mirrors can compare against what they already
+// have before re-downloading the full record.
+func writeIndex(osvData map[cves.CVEID]*vulns.Vulnerability, osvOutputPath string) error {
+	entries := make([]indexEntry, 0, len(osvData))
+	for id, osv := range osvData {
+		entries = append(entries, indexEntry{ID: string(id), Modified: osv.Modified})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return writeJSONFile(path.Join(osvOutputPath, "index.json"), entries)
+}
+
+// writeByEcosystem writes by-ecosystem/<ecosystem>/<package>.json, each
+// listing the IDs of every OSV record affecting that package. It returns
+// every per-package write failure it encountered rather than aborting the
+// whole run on the first one, since a single oddly-named package shouldn't
+// take down an otherwise-successful run.
+func writeByEcosystem(osvData map[cves.CVEID]*vulns.Vulnerability, osvOutputPath string) []error {
+	idsByEcosystemPackage := map[string]map[string][]string{}
+	for id, osv := range osvData {
+		seenPackage := map[string]bool{}
+		for _, affected := range osv.Affected {
+			key := affected.Package.Ecosystem + "/" + affected.Package.Name
+			if seenPackage[key] {
+				continue
+			}
+			seenPackage[key] = true
+
+			byPackage, ok := idsByEcosystemPackage[affected.Package.Ecosystem]
+			if !ok {
+				byPackage = map[string][]string{}
+				idsByEcosystemPackage[affected.Package.Ecosystem] = byPackage
+			}
+			byPackage[affected.Package.Name] = append(byPackage[affected.Package.Name], string(id))
+		}
+	}
+
+	var errs []error
+	for ecosystem, byPackage := range idsByEcosystemPackage {
+		ecosystemDir := path.Join(osvOutputPath, "by-ecosystem", ecosystem)
+		for packageName, ids := range byPackage {
+			sort.Strings(ids)
+
+			// Package names routinely contain "/" (npm scoped packages,
+			// Go module paths, Packagist "vendor/package", ...), which
+			// would otherwise turn packageName+".json" into a multi-segment
+			// path whose parent directories were never created.
+			packagePath := path.Join(ecosystemDir, packageName+".json")
+			if err := os.MkdirAll(path.Dir(packagePath), 0755); err != nil {
+				errs = append(errs, fmt.Errorf("failed to create %q: %w", path.Dir(packagePath), err))
+				continue
+			}
+			if err := writeJSONFile(packagePath, ids); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// writeManifest writes manifest.json, recording what produced the dump in
+// osvOutputPath and from what inputs.
+func writeManifest(osvOutputPath string, inputCveCount, outputCount int, partsSourceSHAs map[string]string, runTimestamp time.Time) error {
+	data := manifestData{
+		GeneratorVersion: generatorVersion,
+		InputCveCount:    inputCveCount,
+		OutputCount:      outputCount,
+		PartsSourceSHAs:  partsSourceSHAs,
+		RunTimestamp:     runTimestamp.Format(time.RFC3339),
+	}
+	return writeJSONFile(path.Join(osvOutputPath, "manifest.json"), data)
+}
+
+// writeJSONFile writes v to filePath as indented JSON, creating or
+// truncating the file as needed.
+func writeJSONFile(filePath string, v any) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create/open file to write: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode %q: %w", filePath, err)
+	}
+	return nil
+}
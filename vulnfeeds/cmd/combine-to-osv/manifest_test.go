@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+func vulnerability(id, ecosystem, pkgName string) *vulns.Vulnerability {
+	return &vulns.Vulnerability{
+		ID:       id,
+		Modified: "2020-01-01T00:00:00Z",
+		Affected: []vulns.Affected{
+			{Package: vulns.Package{Ecosystem: ecosystem, Name: pkgName}},
+		},
+	}
+}
+
+func readIDs(t *testing.T, path string) []string {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %q: %v", path, err)
+	}
+	var ids []string
+	if err := json.Unmarshal(contents, &ids); err != nil {
+		t.Fatalf("failed to decode %q: %v", path, err)
+	}
+	return ids
+}
+
+// TestWriteByEcosystemNestedPackageName guards against the bug fixed in
+// aacd400: package names containing "/" (npm scoped packages, Go module
+// paths, Packagist "vendor/package") need their parent directories created
+// before the per-package JSON file can be written.
+func TestWriteByEcosystemNestedPackageName(t *testing.T) {
+	dir := t.TempDir()
+	osvData := map[cves.CVEID]*vulns.Vulnerability{
+		"CVE-2020-0001": vulnerability("CVE-2020-0001", "npm", "@scope/name"),
+	}
+
+	if errs := writeByEcosystem(osvData, dir); len(errs) != 0 {
+		t.Fatalf("writeByEcosystem() returned unexpected errors: %v", errs)
+	}
+
+	got := readIDs(t, filepath.Join(dir, "by-ecosystem", "npm", "@scope", "name.json"))
+	want := []string{"CVE-2020-0001"}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("by-ecosystem/npm/@scope/name.json = %v, want %v", got, want)
+	}
+}
+
+// TestWriteByEcosystemOneFailureDoesNotBlockOthers guards against the other
+// half of aacd400: a single package whose write fails (here, because a
+// parent path segment is already a regular file) must not prevent the rest
+// of the run's by-ecosystem listings from being written.
+func TestWriteByEcosystemOneFailureDoesNotBlockOthers(t *testing.T) {
+	dir := t.TempDir()
+
+	// Pre-create a regular file where writeByEcosystem would need to
+	// create a directory for the "broken/sub" package, so its MkdirAll
+	// fails while every other package is unaffected.
+	blockedDir := filepath.Join(dir, "by-ecosystem", "PyPI")
+	if err := os.MkdirAll(blockedDir, 0755); err != nil {
+		t.Fatalf("failed to set up test fixture: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(blockedDir, "broken"), []byte("not a directory"), 0644); err != nil {
+		t.Fatalf("failed to set up test fixture: %v", err)
+	}
+
+	osvData := map[cves.CVEID]*vulns.Vulnerability{
+		"CVE-2020-0001": vulnerability("CVE-2020-0001", "PyPI", "broken/sub"),
+		"CVE-2020-0002": vulnerability("CVE-2020-0002", "PyPI", "normal"),
+	}
+
+	errs := writeByEcosystem(osvData, dir)
+	if len(errs) != 1 {
+		t.Fatalf("writeByEcosystem() returned %d errors, want 1: %v", len(errs), errs)
+	}
+
+	got := readIDs(t, filepath.Join(dir, "by-ecosystem", "PyPI", "normal.json"))
+	want := []string{"CVE-2020-0002"}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("by-ecosystem/PyPI/normal.json = %v, want %v (should still be written despite the other package's failure)", got, want)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	dir := t.TempDir()
+	osvData := map[cves.CVEID]*vulns.Vulnerability{
+		"CVE-2020-0001": {ID: "CVE-2020-0001", Modified: "2020-01-01T00:00:00Z"},
+	}
+
+	if err := writeIndex(osvData, dir); err != nil {
+		t.Fatalf("writeIndex() returned error: %v", err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatalf("failed to read index.json: %v", err)
+	}
+	var entries []indexEntry
+	if err := json.Unmarshal(contents, &entries); err != nil {
+		t.Fatalf("failed to decode index.json: %v", err)
+	}
+	want := []indexEntry{{ID: "CVE-2020-0001", Modified: "2020-01-01T00:00:00Z"}}
+	if len(entries) != 1 || entries[0] != want[0] {
+		t.Errorf("index.json entries = %+v, want %+v", entries, want)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/vulns"
+	"gopkg.in/yaml.v3"
+)
+
+// gitPartsSource is one -partsGit entry: an ecosystem and the upstream repo
+// that carries its security advisories.
+type gitPartsSource struct {
+	Ecosystem string
+	RepoURL   string
+}
+
+// parseGitPartsSources parses a -partsGit flag value of the form
+// "alpine=https://github.com/alpinelinux/alpine-secdb,debian=https://...".
+func parseGitPartsSources(flagValue string) ([]gitPartsSource, error) {
+	if flagValue == "" {
+		return nil, nil
+	}
+	var sources []gitPartsSource
+	for _, entry := range strings.Split(flagValue, ",") {
+		ecosystem, repoURL, found := strings.Cut(entry, "=")
+		if !found || ecosystem == "" || repoURL == "" {
+			return nil, fmt.Errorf("malformed -partsGit entry %q, want ecosystem=url", entry)
+		}
+		sources = append(sources, gitPartsSource{Ecosystem: ecosystem, RepoURL: repoURL})
+	}
+	return sources, nil
+}
+
+// cloneOrPull makes cacheDir a checkout of repoURL: clones it if cacheDir
+// isn't one already, otherwise pulls. The clone is full-history (not
+// shallow): commitAuthorTime needs to walk each file's own commit history,
+// which a --depth 1 clone would collapse to a single commit shared by every
+// file in the repo.
+func cloneOrPull(repoURL, cacheDir string) error {
+	if _, err := os.Stat(path.Join(cacheDir, ".git")); os.IsNotExist(err) {
+		if out, err := exec.Command("git", "clone", repoURL, cacheDir).CombinedOutput(); err != nil {
+			return fmt.Errorf("git clone %q failed: %w: %s", repoURL, err, out)
+		}
+		return nil
+	}
+	if out, err := exec.Command("git", "-C", cacheDir, "pull", "--ff-only").CombinedOutput(); err != nil {
+		return fmt.Errorf("git pull in %q failed: %w: %s", cacheDir, err, out)
+	}
+	return nil
+}
+
+// headCommitSHA returns the commit cacheDir is checked out at, for recording
+// in manifest.json's parts_source_shas.
+func headCommitSHA(cacheDir string) (string, error) {
+	out, err := exec.Command("git", "-C", cacheDir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD in %q failed: %w", cacheDir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// commitAuthorTime returns the author time of the most recent commit that
+// touched relPath within repoDir. Unlike getModifiedTime's os.Stat-based
+// mtime, this survives the generator running somewhere (e.g. GCS) where
+// on-disk mtimes don't reflect when the advisory actually changed upstream.
+func commitAuthorTime(repoDir, relPath string) (time.Time, error) {
+	out, err := exec.Command("git", "-C", repoDir, "log", "-1", "--format=%aI", "--", relPath).Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log for %q in %q failed: %w", relPath, repoDir, err)
+	}
+	trimmed := strings.TrimSpace(string(out))
+	if trimmed == "" {
+		return time.Time{}, fmt.Errorf("no commit history for %q in %q", relPath, repoDir)
+	}
+	return time.Parse(time.RFC3339, trimmed)
+}
+
+// gitAdvisoryParser turns one native advisory file from a cloned upstream
+// repo into the PackageInfos it describes, keyed by the CVE IDs it fixes.
+type gitAdvisoryParser func(relPath string, contents []byte) (map[cves.CVEID][]vulns.PackageInfo, error)
+
+// gitAdvisorySource pairs the file suffix that selects an ecosystem's
+// advisory files with the parser used to read them.
+type gitAdvisorySource struct {
+	suffix string
+	parse  gitAdvisoryParser
+}
+
+// gitAdvisoryParsers maps an ecosystem name (lowercased) to how its upstream
+// repo's native advisory format is read.
+var gitAdvisoryParsers = map[string]gitAdvisorySource{
+	"alpine": {suffix: ".yaml", parse: parseAlpineSecdbYAML},
+	"debian": {suffix: ".json", parse: parseDebianSecurityTrackerJSON},
+}
+
+// alpineSecdb mirrors the subset of alpine-secdb's per-branch YAML schema
+// (e.g. community/v3.18/secdb.yaml) this generator cares about.
+type alpineSecdb struct {
+	Packages []struct {
+		Pkg struct {
+			Name     string            `yaml:"name"`
+			Secfixes map[string]string `yaml:"secfixes"`
+		} `yaml:"pkg"`
+	} `yaml:"packages"`
+}
+
+// parseAlpineSecdbYAML parses one alpine-secdb branch file into the CVEs it
+// fixes for each package in that branch.
+func parseAlpineSecdbYAML(relPath string, contents []byte) (map[cves.CVEID][]vulns.PackageInfo, error) {
+	var secdb alpineSecdb
+	if err := yaml.Unmarshal(contents, &secdb); err != nil {
+		return nil, fmt.Errorf("failed to parse alpine secdb %q: %w", relPath, err)
+	}
+
+	// community/v3.18/secdb.yaml -> v3.18
+	branch := path.Base(path.Dir(relPath))
+
+	result := map[cves.CVEID][]vulns.PackageInfo{}
+	for _, pkg := range secdb.Packages {
+		for fixedVersion, cveList := range pkg.Pkg.Secfixes {
+			for _, cveId := range strings.Fields(cveList) {
+				result[cves.CVEID(cveId)] = append(result[cves.CVEID(cveId)], vulns.PackageInfo{
+					PkgName:      pkg.Pkg.Name,
+					Ecosystem:    "Alpine:" + branch,
+					FixedVersion: fixedVersion,
+				})
+			}
+		}
+	}
+	return result, nil
+}
+
+// debianSecurityTrackerEntry mirrors the subset of the Debian security
+// tracker's per-package JSON export this generator cares about.
+type debianSecurityTrackerEntry struct {
+	Releases map[string]struct {
+		FixedVersion string `json:"fixed_version"`
+		Status       string `json:"status"`
+	} `json:"releases"`
+}
+
+// parseDebianSecurityTrackerJSON parses one package's exported CVE-to-status
+// mapping into the CVEs it's been fixed for.
+func parseDebianSecurityTrackerJSON(relPath string, contents []byte) (map[cves.CVEID][]vulns.PackageInfo, error) {
+	// The tracker exports one file per package, named "<package>.json".
+	pkgName := strings.TrimSuffix(path.Base(relPath), ".json")
+
+	var byCve map[string]debianSecurityTrackerEntry
+	if err := json.Unmarshal(contents, &byCve); err != nil {
+		return nil, fmt.Errorf("failed to parse debian security tracker entry %q: %w", relPath, err)
+	}
+
+	result := map[cves.CVEID][]vulns.PackageInfo{}
+	for cveId, entry := range byCve {
+		for release, info := range entry.Releases {
+			if info.Status != "resolved" || info.FixedVersion == "" {
+				continue
+			}
+			result[cves.CVEID(cveId)] = append(result[cves.CVEID(cveId)], vulns.PackageInfo{
+				PkgName:      pkgName,
+				Ecosystem:    "Debian:" + release,
+				FixedVersion: info.FixedVersion,
+			})
+		}
+	}
+	return result, nil
+}
+
+// loadGitParts clones or updates every configured -partsGit source into
+// cacheRoot, parses its native advisory format, and adds the results to out
+// using each file's upstream commit author time rather than its on-disk
+// mtime (see commitAuthorTime). It returns the HEAD commit SHA of each
+// cloned source, for recording in manifest.json.
+func loadGitParts(sources []gitPartsSource, cacheRoot string, out *partsOutput, workers int) (map[string]string, []error) {
+	shas := map[string]string{}
+	var errs []error
+
+	for _, source := range sources {
+		advisorySource, ok := gitAdvisoryParsers[strings.ToLower(source.Ecosystem)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("no git advisory parser registered for ecosystem %q", source.Ecosystem))
+			continue
+		}
+
+		repoDir := path.Join(cacheRoot, strings.ToLower(source.Ecosystem))
+		if err := cloneOrPull(source.RepoURL, repoDir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if sha, err := headCommitSHA(repoDir); err != nil {
+			errs = append(errs, err)
+		} else {
+			shas[source.Ecosystem] = sha
+		}
+
+		var advisoryFiles []string
+		err := filepath.WalkDir(repoDir, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(filePath, advisorySource.suffix) {
+				return err
+			}
+			advisoryFiles = append(advisoryFiles, filePath)
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to walk %q: %w", repoDir, err))
+			continue
+		}
+
+		var errsMu sync.Mutex
+		touchedMu := sync.Mutex{}
+		touched := map[cves.CVEID]bool{}
+		runWorkerPool(workers, advisoryFiles, func(filePath string) {
+			relPath, _ := filepath.Rel(repoDir, filePath)
+			contents, err := os.ReadFile(filePath)
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, fmt.Errorf("failed to read %q: %w", filePath, err))
+				errsMu.Unlock()
+				return
+			}
+
+			parsed, err := advisorySource.parse(relPath, contents)
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+				return
+			}
+
+			modifiedTime, err := commitAuthorTime(repoDir, relPath)
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+				return
+			}
+
+			touchedMu.Lock()
+			for cveId, pkgInfos := range parsed {
+				out.add(cveId, pkgInfos, modifiedTime)
+				touched[cveId] = true
+			}
+			touchedMu.Unlock()
+		})
+
+		// Several advisory files commonly contribute PackageInfos to the
+		// same CVE (e.g. the same CVE fixed across multiple Alpine branches
+		// or Debian releases), and those files are processed concurrently
+		// above, so the order they landed in out.output is nondeterministic.
+		// Re-sort deterministically so re-running produces byte-identical
+		// output (which incremental mode's vulnerabilityContentEqual
+		// depends on).
+		out.sortEntries(touched)
+	}
+
+	return shas, errs
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGitPartsSources(t *testing.T) {
+	got, err := parseGitPartsSources("alpine=https://example.com/alpine,debian=https://example.com/debian")
+	if err != nil {
+		t.Fatalf("parseGitPartsSources() returned error: %v", err)
+	}
+	want := []gitPartsSource{
+		{Ecosystem: "alpine", RepoURL: "https://example.com/alpine"},
+		{Ecosystem: "debian", RepoURL: "https://example.com/debian"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitPartsSources() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseGitPartsSourcesEmpty(t *testing.T) {
+	got, err := parseGitPartsSources("")
+	if err != nil {
+		t.Fatalf(`parseGitPartsSources("") returned error: %v`, err)
+	}
+	if got != nil {
+		t.Errorf(`parseGitPartsSources("") = %+v, want nil`, got)
+	}
+}
+
+func TestParseGitPartsSourcesMalformed(t *testing.T) {
+	tests := []string{
+		"alpine",
+		"=https://example.com",
+		"alpine=",
+		"alpine=https://example.com,",
+	}
+	for _, entry := range tests {
+		if _, err := parseGitPartsSources(entry); err == nil {
+			t.Errorf("parseGitPartsSources(%q) should return an error", entry)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "sync"
+
+// runWorkerPool runs fn once for each item in items, using at most workers
+// goroutines concurrently, and blocks until every item has been processed.
+func runWorkerPool[T any](workers int, items []T, fn func(T)) {
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan T)
+	var wg sync.WaitGroup
+	for range make([]struct{}, workers) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				fn(item)
+			}
+		}()
+	}
+	for _, item := range items {
+		jobs <- item
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/utility"
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+// TestMain initializes the package-level Logger the same way main() does,
+// since writeOSVFile and friends log unconditionally and would otherwise
+// panic against Logger's zero value.
+func TestMain(m *testing.M) {
+	var cleanup func()
+	Logger, cleanup = utility.CreateLoggerWrapper("combine-to-osv-test")
+	code := m.Run()
+	cleanup()
+	os.Exit(code)
+}
+
+func TestRunWorkerPoolProcessesEveryItem(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	var mu sync.Mutex
+	var seen []int
+	runWorkerPool(3, items, func(item int) {
+		mu.Lock()
+		seen = append(seen, item)
+		mu.Unlock()
+	})
+
+	sort.Ints(seen)
+	if !reflect.DeepEqual(seen, items) {
+		t.Errorf("runWorkerPool visited %v, want every item in %v exactly once (order aside)", seen, items)
+	}
+}
+
+func TestRunWorkerPoolClampsWorkersBelowOne(t *testing.T) {
+	items := []int{1, 2, 3}
+	for _, workers := range []int{0, -1, -100} {
+		var mu sync.Mutex
+		var count int
+		runWorkerPool(workers, items, func(int) {
+			mu.Lock()
+			count++
+			mu.Unlock()
+		})
+		if count != len(items) {
+			t.Errorf("runWorkerPool(%d, ...) processed %d items, want %d", workers, count, len(items))
+		}
+	}
+}
+
+func TestRunWorkerPoolEmptyItems(t *testing.T) {
+	called := false
+	runWorkerPool(4, []int{}, func(int) { called = true })
+	if called {
+		t.Error("runWorkerPool with no items should not call fn")
+	}
+}
+
+// TestWriteOSVFileTruncatesOnRewrite guards against the bug fixed in
+// fc2d39d: writing a shorter record over a previously-written longer one
+// must not leave trailing bytes of the old content behind.
+func TestWriteOSVFileTruncatesOnRewrite(t *testing.T) {
+	dir := t.TempDir()
+	id := cves.CVEID("CVE-2020-1234")
+
+	long := map[cves.CVEID]*vulns.Vulnerability{
+		id: {
+			ID:       string(id),
+			Modified: "2020-01-01T00:00:00Z",
+			Aliases:  []string{"CVE-0000-00000000000000000000000000000000000"},
+		},
+	}
+	writeOSVFile(long, dir, 1)
+
+	short := map[cves.CVEID]*vulns.Vulnerability{
+		id: {ID: string(id), Modified: "2020-01-01T00:00:00Z"},
+	}
+	writeOSVFile(short, dir, 1)
+
+	contents, err := os.ReadFile(filepath.Join(dir, string(id)+".json"))
+	if err != nil {
+		t.Fatalf("failed to read written file: %v", err)
+	}
+
+	var decoded vulns.Vulnerability
+	if err := json.Unmarshal(contents, &decoded); err != nil {
+		t.Fatalf("rewritten file is not valid JSON, likely trailing garbage bytes from the previous longer write: %v\ncontents: %s", err, contents)
+	}
+	if len(decoded.Aliases) != 0 {
+		t.Errorf("expected the shorter rewrite to have fully replaced the longer content, got Aliases=%v", decoded.Aliases)
+	}
+}
@@ -0,0 +1,134 @@
+// Package osvlint checks generated OSV records for structural problems
+// before they're written out, so a malformed record can be caught and
+// excluded instead of silently shipping to consumers.
+package osvlint
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/osv/vulnfeeds/cves"
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+// Mode controls what a caller does with a record that fails a Lint check.
+type Mode string
+
+const (
+	// ModeWarn logs every finding but keeps every record in the output.
+	ModeWarn Mode = "warn"
+	// ModeFail logs every finding and excludes the offending record from
+	// the output, without aborting the rest of the run.
+	ModeFail Mode = "fail"
+	// ModeSkip disables linting entirely.
+	ModeSkip Mode = "skip"
+)
+
+// ParseMode validates a -lintMode flag value.
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case ModeWarn, ModeFail, ModeSkip:
+		return Mode(value), nil
+	default:
+		return "", fmt.Errorf("unknown lint mode %q, want one of %q, %q, %q", value, ModeWarn, ModeFail, ModeSkip)
+	}
+}
+
+// Finding is one lint violation against a single OSV record.
+type Finding struct {
+	ID      string `json:"id"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// KnownEcosystem reports whether ecosystem is a recognized one, so Lint can
+// flag typos and unregistered ecosystems early.
+type KnownEcosystem func(ecosystem string) bool
+
+// knownEcosystemBases is the set of base OSV ecosystem names recognized by
+// the schema (https://ossf.github.io/osv-schema/#affectedpackage-field).
+// An ecosystem value may carry a ":<version>" suffix (e.g. "Alpine:v3.12",
+// "Debian:11"); only the part before the ":" is checked against this set.
+// This is deliberately independent of the combine-to-osv security-tracker
+// registry (request #chunk0-1), which only lists the handful of ecosystems
+// that have a tracker URL, not every ecosystem OSV records can carry.
+var knownEcosystemBases = map[string]bool{
+	"AlmaLinux": true, "Alpaquita": true, "Alpine": true, "Android": true,
+	"Bioconductor": true, "Bitnami": true, "Chainguard": true, "conda": true,
+	"CRAN": true, "crates.io": true, "Debian": true, "GHC": true,
+	"GitHub Actions": true, "Go": true, "Hackage": true, "Hex": true,
+	"Kubernetes": true, "Linux": true, "Mageia": true, "Maven": true,
+	"MinimOS": true, "npm": true, "NuGet": true, "openEuler": true,
+	"openSUSE": true, "OSS-Fuzz": true, "Packagist": true, "Photon OS": true,
+	"Pub": true, "PyPI": true, "Red Hat": true, "Rocky Linux": true,
+	"RubyGems": true, "SUSE": true, "SwiftURL": true, "Ubuntu": true,
+	"Wolfi": true,
+}
+
+// IsKnownEcosystem reports whether ecosystem (optionally carrying a
+// ":<version>" suffix) is a recognized OSV ecosystem. Use this as the
+// KnownEcosystem passed to Lint unless the caller has its own more precise
+// list (e.g. one restricted to ecosystems it actually generates records
+// for).
+func IsKnownEcosystem(ecosystem string) bool {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return knownEcosystemBases[base]
+}
+
+var knownReferenceTypes = map[string]bool{
+	"ADVISORY": true, "ARTICLE": true, "DETECTION": true, "DISCUSSION": true,
+	"REPORT": true, "FIX": true, "INTRODUCED": true, "PACKAGE": true,
+	"EVIDENCE": true, "WEB": true,
+}
+
+// Lint checks a single OSV record and returns every violation found.
+// knownEcosystem may be nil, in which case the ecosystem-recognition rule
+// is skipped.
+func Lint(id cves.CVEID, osv *vulns.Vulnerability, knownEcosystem KnownEcosystem) []Finding {
+	var findings []Finding
+	report := func(rule, format string, args ...any) {
+		findings = append(findings, Finding{ID: string(id), Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(osv.Affected) == 0 {
+		report("has-affected", "record has no Affected entries")
+	}
+	for i, affected := range osv.Affected {
+		if len(affected.Ranges) == 0 && len(affected.Versions) == 0 {
+			report("affected-has-versions", "Affected[%d] (%s) has neither Ranges nor Versions", i, affected.Package.Name)
+		}
+		if knownEcosystem != nil && !knownEcosystem(affected.Package.Ecosystem) {
+			report("known-ecosystem", "Affected[%d] has unrecognized ecosystem %q", i, affected.Package.Ecosystem)
+		}
+	}
+
+	published, pubErr := time.Parse(time.RFC3339, osv.Published)
+	modified, modErr := time.Parse(time.RFC3339, osv.Modified)
+	if pubErr == nil && modErr == nil && modified.Before(published) {
+		report("modified-after-published", "Modified (%s) is before Published (%s)", osv.Modified, osv.Published)
+	}
+
+	for i, ref := range osv.References {
+		if ref.URL == "" {
+			report("reference-has-url", "References[%d] has an empty URL", i)
+		}
+		if !knownReferenceTypes[ref.Type] {
+			report("reference-known-type", "References[%d] has unrecognized Type %q", i, ref.Type)
+		}
+	}
+
+	if osv.Withdrawn != "" {
+		if _, err := time.Parse(time.RFC3339, osv.Withdrawn); err != nil {
+			report("withdrawn-is-rfc3339", "Withdrawn %q does not parse as RFC3339: %s", osv.Withdrawn, err)
+		}
+	}
+
+	for _, alias := range osv.Aliases {
+		if alias == osv.ID {
+			report("aliases-no-self-reference", "Aliases contains the record's own ID %q", alias)
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,128 @@
+package osvlint
+
+import (
+	"testing"
+
+	"github.com/google/osv/vulnfeeds/vulns"
+)
+
+func hasRule(findings []Finding, rule string) bool {
+	for _, f := range findings {
+		if f.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLintHasAffected(t *testing.T) {
+	osv := &vulns.Vulnerability{ID: "CVE-2020-1234", Published: "2020-01-01T00:00:00Z", Modified: "2020-01-02T00:00:00Z"}
+	findings := Lint("CVE-2020-1234", osv, nil)
+	if !hasRule(findings, "has-affected") {
+		t.Errorf("expected a has-affected finding for a record with no Affected entries, got %+v", findings)
+	}
+}
+
+func TestLintAffectedHasVersions(t *testing.T) {
+	osv := &vulns.Vulnerability{
+		ID:        "CVE-2020-1234",
+		Published: "2020-01-01T00:00:00Z",
+		Modified:  "2020-01-02T00:00:00Z",
+		Affected: []vulns.Affected{
+			{Package: vulns.Package{Ecosystem: "PyPI", Name: "example"}},
+		},
+	}
+	findings := Lint("CVE-2020-1234", osv, nil)
+	if !hasRule(findings, "affected-has-versions") {
+		t.Errorf("expected an affected-has-versions finding for an Affected entry with no Ranges or Versions, got %+v", findings)
+	}
+}
+
+func TestLintModifiedAfterPublished(t *testing.T) {
+	osv := &vulns.Vulnerability{
+		ID:        "CVE-2020-1234",
+		Published: "2020-06-01T00:00:00Z",
+		Modified:  "2020-01-01T00:00:00Z",
+	}
+	findings := Lint("CVE-2020-1234", osv, nil)
+	if !hasRule(findings, "modified-after-published") {
+		t.Errorf("expected a modified-after-published finding, got %+v", findings)
+	}
+}
+
+func TestLintReferenceRules(t *testing.T) {
+	osv := &vulns.Vulnerability{
+		ID:        "CVE-2020-1234",
+		Published: "2020-01-01T00:00:00Z",
+		Modified:  "2020-01-02T00:00:00Z",
+		References: []vulns.Reference{
+			{Type: "ADVISORY", URL: ""},
+			{Type: "BOGUS", URL: "https://example.com"},
+		},
+	}
+	findings := Lint("CVE-2020-1234", osv, nil)
+	if !hasRule(findings, "reference-has-url") {
+		t.Errorf("expected a reference-has-url finding, got %+v", findings)
+	}
+	if !hasRule(findings, "reference-known-type") {
+		t.Errorf("expected a reference-known-type finding, got %+v", findings)
+	}
+}
+
+func TestLintWithdrawnRFC3339(t *testing.T) {
+	osv := &vulns.Vulnerability{
+		ID:        "CVE-2020-1234",
+		Published: "2020-01-01T00:00:00Z",
+		Modified:  "2020-01-02T00:00:00Z",
+		Withdrawn: "not-a-date",
+	}
+	findings := Lint("CVE-2020-1234", osv, nil)
+	if !hasRule(findings, "withdrawn-is-rfc3339") {
+		t.Errorf("expected a withdrawn-is-rfc3339 finding, got %+v", findings)
+	}
+}
+
+func TestLintAliasesNoSelfReference(t *testing.T) {
+	osv := &vulns.Vulnerability{
+		ID:        "CVE-2020-1234",
+		Published: "2020-01-01T00:00:00Z",
+		Modified:  "2020-01-02T00:00:00Z",
+		Aliases:   []string{"CVE-2020-1234"},
+	}
+	findings := Lint("CVE-2020-1234", osv, nil)
+	if !hasRule(findings, "aliases-no-self-reference") {
+		t.Errorf("expected an aliases-no-self-reference finding, got %+v", findings)
+	}
+}
+
+func TestLintKnownEcosystem(t *testing.T) {
+	osv := &vulns.Vulnerability{
+		ID:        "CVE-2020-1234",
+		Published: "2020-01-01T00:00:00Z",
+		Modified:  "2020-01-02T00:00:00Z",
+		Affected: []vulns.Affected{
+			{Package: vulns.Package{Ecosystem: "Bogus", Name: "example"}, Versions: []string{"1.0"}},
+		},
+	}
+	findings := Lint("CVE-2020-1234", osv, IsKnownEcosystem)
+	if !hasRule(findings, "known-ecosystem") {
+		t.Errorf("expected a known-ecosystem finding for an unrecognized ecosystem, got %+v", findings)
+	}
+}
+
+func TestIsKnownEcosystem(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		want      bool
+	}{
+		{"PyPI", true},
+		{"Alpine:v3.18", true},
+		{"Debian:11", true},
+		{"Bogus", false},
+	}
+	for _, tc := range tests {
+		if got := IsKnownEcosystem(tc.ecosystem); got != tc.want {
+			t.Errorf("IsKnownEcosystem(%q) = %v, want %v", tc.ecosystem, got, tc.want)
+		}
+	}
+}